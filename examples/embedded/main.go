@@ -0,0 +1,39 @@
+// Command embedded demonstrates staticgen's library API: it embeds an
+// entire source tree into the binary with go:embed and regenerates the site
+// at runtime with no source tree on disk, which is handy for serverless or
+// CI containers that only ship the built binary.
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+
+	"github.com/zacsketches/staticgen/staticgen"
+)
+
+// all: is required because staticgen's own conventions (_layouts, _includes,
+// _data, _config.yaml) are all underscore/dot-prefixed, which go:embed
+// otherwise treats as hidden and skips.
+//
+//go:embed all:src
+var embeddedSrc embed.FS
+
+func main() {
+	// go:embed roots the tree at "src", but RenderSite wants an fs.FS rooted
+	// at the source directory itself (pages/, _includes/, etc).
+	in, err := fs.Sub(embeddedSrc, "src")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := staticgen.Options{
+		PagesGlob:      "pages/**/*.template.html",
+		MDGlob:         "pages/**/*.md",
+		BuildTimestamp: "embedded build",
+		Jobs:           1,
+	}
+	if err := staticgen.RenderSite(in, "./site", opts); err != nil {
+		log.Fatal(err)
+	}
+}
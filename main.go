@@ -3,29 +3,29 @@ package main
 import (
 	"errors"
 	"flag"
-	"fmt"
-	"html/template"
 	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
-	"strings"
+	"runtime"
 	"time"
-)
 
-type BaseData struct {
-	Year           int
-	BuildTimestamp string
-	// Add anything you want available to every page:
-	// UserName string
-	// Env      string
-}
+	"github.com/zacsketches/staticgen/staticgen"
+)
 
 func main() {
-	var srcDir, outDir, pagesGlob, buildTimestamp string
+	var srcDir, outDir, pagesGlob, mdGlob, buildTimestamp, addr string
+	var jobs int
+	var serial, serve, doMinify, collectionsFlag bool
 	flag.StringVar(&srcDir, "src", "./src", "source directory")
 	flag.StringVar(&outDir, "out", "./site", "output directory")
 	flag.StringVar(&pagesGlob, "glob", "pages/**/*.template.html", "glob for pages within src directory")
+	flag.StringVar(&mdGlob, "mdglob", "pages/**/*.md", "glob for Markdown pages within src directory")
+	flag.IntVar(&jobs, "jobs", runtime.NumCPU(), "number of pages to render concurrently")
+	flag.BoolVar(&serial, "serial", false, "render pages one at a time, in order, for deterministic logs")
+	flag.BoolVar(&serve, "serve", false, "serve outDir over HTTP and rebuild on source changes")
+	flag.StringVar(&addr, "addr", ":8080", "address for -serve to listen on")
+	flag.BoolVar(&doMinify, "minify", false, "minify rendered HTML")
+	flag.BoolVar(&collectionsFlag, "collections", false, "build Prev/Next and tag indexes from Markdown pages")
 
 	// Load CST timezone
 	cst, err := time.LoadLocation("America/Chicago")
@@ -36,113 +36,35 @@ func main() {
 	flag.StringVar(&buildTimestamp, "timestamp", defaultTimestamp, "build timestamp")
 	flag.Parse()
 
+	if serial {
+		jobs = 1
+	}
+	opts := staticgen.Options{
+		PagesGlob:      pagesGlob,
+		MDGlob:         mdGlob,
+		BuildTimestamp: buildTimestamp,
+		Jobs:           jobs,
+		Minify:         doMinify,
+		Collections:    collectionsFlag,
+	}
+
 	log.Printf("Starting static site generation...")
 	log.Printf("Source directory: %s", srcDir)
 	log.Printf("Output directory: %s", outDir)
 	log.Printf("Build timestamp: %s", buildTimestamp)
 
-	// Ensure output dir exists
-	if err := os.MkdirAll(outDir, 0o755); err != nil {
-		fatal(err)
-	}
-
-	// Collect page files
-	pagePattern := filepath.Join(srcDir, pagesGlob)
-	pageFiles, err := filepath.Glob(pagePattern)
-	if err != nil {
-		fatal(err)
-	}
-	if len(pageFiles) == 0 {
-		fatal(errors.New("no page templates found: " + pagePattern))
-	}
-
-	log.Printf("Found %d page template(s) to render", len(pageFiles))
-
-	// Common includes/layouts
-	includesGlobs := []string{
-		filepath.Join(srcDir, "_includes", "*.html"),
-		filepath.Join(srcDir, "_layouts", "*.html"),
-	}
-
-	for _, page := range pageFiles {
-		if err := renderOne(srcDir, outDir, includesGlobs, page, buildTimestamp); err != nil {
+	if serve {
+		if err := runServe(srcDir, outDir, addr, opts); err != nil {
 			fatal(err)
 		}
+		return
 	}
 
-	log.Printf("Static site generation completed successfully")
-}
-
-func renderOne(srcDir, outDir string, includesGlobs []string, pageFile string, buildTimestamp string) error {
-	// Build the full list of template files for this page
-	var all []string
-	for _, g := range includesGlobs {
-		matches, _ := filepath.Glob(g)
-		all = append(all, matches...)
-	}
-	all = append(all, pageFile)
-
-	// Parse as one set so blocks/partials can see each other
-	funcs := template.FuncMap{
-		// Add helper funcs as needed
-		"nowRFC3339": func() string { return time.Now().Format(time.RFC3339) },
-	}
-	t, err := template.New("root").Funcs(funcs).ParseFiles(all...)
-	if err != nil {
-		return err
-	}
-
-	// Default layout
-	layout := "public"
-
-	// Detect optional {{define "layout_name"}}dashboard{{end}} etc.
-	if t.Lookup("layout_name") != nil {
-		var b strings.Builder
-		if err := t.ExecuteTemplate(&b, "layout_name", nil); err == nil {
-			name := strings.TrimSpace(b.String())
-			if name != "" {
-				layout = name
-			}
-		}
-	}
-
-	// Determine output path: pages/foo.template.html -> site/foo.html
-	rel, err := filepath.Rel(filepath.Join(srcDir, "pages"), pageFile)
-	if err != nil {
-		return err
-	}
-	outName := strings.TrimSuffix(rel, ".template.html") + ".html"
-	outPath := filepath.Join(outDir, outName)
-
-	// Log the rendering operation
-	log.Printf("Rendering %s -> %s (layout: %s)", pageFile, outPath, layout)
-
-	// Ensure subdirs exist
-	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
-		return err
-	}
-
-	// Create/write the file
-	f, err := os.Create(outPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	data := BaseData{
-		Year:           time.Now().Year(),
-		BuildTimestamp: buildTimestamp,
-	}
-	// Execute the top-level layout so it pulls in header/footer and the page blocks
-	if err := t.ExecuteTemplate(f, layout, data); err != nil {
-		return fmt.Errorf("%s: executing layout %q: %w", pageFile, layout, err)
+	if err := staticgen.RenderSite(os.DirFS(srcDir), outDir, opts); err != nil {
+		fatal(err)
 	}
 
-	// Log successful completion
-	log.Printf("Successfully wrote %s", outPath)
-
-	// Optional: fmt the HTML, minify, etc.
-	return nil
+	log.Printf("Static site generation completed successfully")
 }
 
 func fatal(err error) {
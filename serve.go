@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/zacsketches/staticgen/staticgen"
+)
+
+// liveReloadScript is injected into every served HTML page; it opens an SSE
+// connection and reloads the tab whenever a rebuild completes.
+const liveReloadScript = `<script>(function(){var es=new EventSource("/__livereload");es.onmessage=function(){location.reload();};})();</script>`
+
+// debounce is how long the watcher waits after the last fs event before
+// kicking off a rebuild, so a save that touches several files (editors doing
+// atomic renames, etc.) only triggers one rebuild.
+const debounce = 200 * time.Millisecond
+
+// devServer holds the state a running -serve instance needs across
+// requests: the last build's error (if any) and the set of browser tabs
+// waiting on a reload.
+type devServer struct {
+	mu       sync.RWMutex
+	buildErr *buildError
+	reload   *reloadHub
+}
+
+// buildError is the subset of a failed rebuild shown in the browser overlay.
+type buildError struct {
+	Path    string
+	Line    int
+	Message string
+	Snippet string
+}
+
+// reloadHub fans a "rebuild happened" event out to every connected
+// /__livereload SSE client.
+type reloadHub struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+func newReloadHub() *reloadHub {
+	return &reloadHub{clients: map[chan struct{}]struct{}{}}
+}
+
+func (h *reloadHub) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *reloadHub) unsubscribe(ch chan struct{}) {
+	h.mu.Lock()
+	delete(h.clients, ch)
+	h.mu.Unlock()
+}
+
+func (h *reloadHub) broadcast() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// runServe boots the dev server: one initial build, an HTTP server over
+// outDir, and an fsnotify watcher on srcDir that rebuilds on change. It never
+// returns under normal operation; the one-shot build path in main is
+// untouched when -serve isn't passed.
+func runServe(srcDir, outDir, addr string, opts staticgen.Options) error {
+	srv := &devServer{reload: newReloadHub()}
+
+	rebuild := func() {
+		err := staticgen.RenderSite(os.DirFS(srcDir), outDir, opts)
+
+		srv.mu.Lock()
+		if err != nil {
+			srv.buildErr = toBuildError(err, srcDir)
+			log.Printf("build error: %v", err)
+		} else {
+			srv.buildErr = nil
+		}
+		srv.mu.Unlock()
+
+		srv.reload.broadcast()
+	}
+	rebuild()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watchTree(watcher, srcDir); err != nil {
+		return err
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(debounce, rebuild)
+			case werr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("watch error: %v", werr)
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/__livereload", srv.handleLiveReload)
+	mux.Handle("/", srv.handleSite(outDir))
+
+	log.Printf("Dev server listening on http://%s", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// watchTree adds every directory under root to watcher; fsnotify doesn't
+// recurse on its own.
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+func (s *devServer) handleLiveReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := s.reload.subscribe()
+	defer s.reload.unsubscribe(ch)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// handleSite serves outDir, but swaps in the error overlay for HTML requests
+// while the last rebuild is broken, and otherwise injects the live-reload
+// script into every HTML response.
+func (s *devServer) handleSite(outDir string) http.Handler {
+	fileServer := http.FileServer(http.Dir(outDir))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		buildErr := s.buildErr
+		s.mu.RUnlock()
+
+		if !isHTMLRequest(r.URL.Path) {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		if buildErr != nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(renderErrorOverlay(buildErr))
+			return
+		}
+
+		injectLiveReload(w, r, fileServer)
+	})
+}
+
+func isHTMLRequest(p string) bool {
+	return p == "" || strings.HasSuffix(p, "/") || strings.HasSuffix(p, ".html")
+}
+
+// bufferedResponseWriter captures a handler's response so it can be rewritten
+// before reaching the real client.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: http.Header{}}
+}
+
+func (w *bufferedResponseWriter) Header() http.Header         { return w.header }
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *bufferedResponseWriter) WriteHeader(status int)      { w.status = status }
+
+// injectLiveReload serves the request through fileServer into a buffer, then
+// appends liveReloadScript just before </body> in the real response.
+func injectLiveReload(w http.ResponseWriter, r *http.Request, fileServer http.Handler) {
+	rec := newBufferedResponseWriter()
+	fileServer.ServeHTTP(rec, r)
+
+	body := rec.body.Bytes()
+	if idx := bytes.LastIndex(body, []byte("</body>")); idx >= 0 {
+		var out bytes.Buffer
+		out.Write(body[:idx])
+		out.WriteString(liveReloadScript)
+		out.Write(body[idx:])
+		body = out.Bytes()
+	}
+
+	for k, v := range rec.header {
+		w.Header()[k] = v
+	}
+	w.Header().Del("Content-Length")
+
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// templateErrorLocation matches the "template: <file>:<line>:" prefix
+// html/template puts on parse and ExecError messages.
+var templateErrorLocation = regexp.MustCompile(`template: ([^:]+):(\d+)`)
+
+// toBuildError unwraps the (possibly joined, see errors.Join) rebuild error
+// down to the first failure and pulls a file/line/snippet out of it. srcDir
+// is searched to turn the bare basename html/template reports back into the
+// real source path (see resolveSourcePath).
+func toBuildError(err error, srcDir string) *buildError {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		if errs := joined.Unwrap(); len(errs) > 0 {
+			err = errs[0]
+		}
+	}
+
+	be := &buildError{Message: err.Error()}
+	if m := templateErrorLocation.FindStringSubmatch(be.Message); m != nil {
+		base := m[1]
+		be.Line, _ = strconv.Atoi(m[2])
+		if real, ok := resolveSourcePath(srcDir, base); ok {
+			be.Path = real
+		} else {
+			be.Path = base
+		}
+		be.Snippet = sourceSnippet(be.Path, be.Line, 5)
+	}
+	return be
+}
+
+// resolveSourcePath finds the file under srcDir whose base name is base.
+// html/template's parse/exec errors report only the basename a page or
+// layout was parsed under (ParseFiles/ParseFS keep just the leaf name as
+// the template name), never its directory, so a bare "post.template.html"
+// has to be matched back to e.g. srcDir/pages/blog/post.template.html
+// before it can be read for the error overlay's source snippet.
+func resolveSourcePath(srcDir, base string) (string, bool) {
+	var found string
+	_ = filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || found != "" {
+			return nil
+		}
+		if !d.IsDir() && d.Name() == base {
+			found = path
+		}
+		return nil
+	})
+	return found, found != ""
+}
+
+// sourceSnippet returns the +/-context lines around line in path, or "" if
+// the file can't be read.
+func sourceSnippet(path string, line, context int) string {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(raw), "\n")
+
+	start := line - context - 1
+	if start < 0 {
+		start = 0
+	}
+	end := line + context
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// renderErrorOverlay is the Hugo-style in-browser error page shown in place
+// of a broken rebuild: path, line, and a source snippet.
+func renderErrorOverlay(b *buildError) []byte {
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html><html><head><title>staticgen: build error</title></head>")
+	page.WriteString(`<body style="font-family:monospace;background:#1e1e1e;color:#f5f5f5;padding:2rem;">`)
+	page.WriteString("<h1>Build error</h1>")
+	if b.Path != "" {
+		fmt.Fprintf(&page, "<p>%s:%d</p>", template.HTMLEscapeString(b.Path), b.Line)
+	}
+	fmt.Fprintf(&page, "<pre>%s</pre>", template.HTMLEscapeString(b.Message))
+	if b.Snippet != "" {
+		fmt.Fprintf(&page, "<pre>%s</pre>", template.HTMLEscapeString(b.Snippet))
+	}
+	page.WriteString(liveReloadScript)
+	page.WriteString("</body></html>")
+	return []byte(page.String())
+}
@@ -0,0 +1,186 @@
+package staticgen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/tdewolff/minify/v2"
+	minifyhtml "github.com/tdewolff/minify/v2/html"
+)
+
+// assetPipeline fingerprints files under in's assets/ into outDir/assets,
+// caching by source-relative path so a file referenced from many pages is
+// only hashed and copied once, and accumulating a manifest.json of
+// original -> fingerprinted names.
+type assetPipeline struct {
+	in     fs.FS
+	outDir string
+
+	mu       sync.Mutex
+	manifest map[string]string
+	// fingerprinted holds every URL (rel path under /assets/) this pipeline
+	// has already emitted, whether from a template's asset()/fingerprint()
+	// call or a prior regex pass, so fingerprintHTML doesn't re-fingerprint
+	// a reference that's already fingerprinted.
+	fingerprinted map[string]bool
+}
+
+func newAssetPipeline(in fs.FS, outDir string) *assetPipeline {
+	return &assetPipeline{in: in, outDir: outDir, manifest: map[string]string{}, fingerprinted: map[string]bool{}}
+}
+
+// assetRefRe matches href/src attributes pointing at /assets/... so the
+// post-process pass can rewrite them to their fingerprinted URL.
+var assetRefRe = regexp.MustCompile(`(href|src)="(/assets/[^"]+)"`)
+
+// fingerprintHTML rewrites every /assets/... reference in html to its
+// fingerprinted URL, copying and hashing the backing file on first use. A
+// reference a template already fingerprinted via asset()/fingerprint() is
+// left untouched: it already points at a fingerprinted output file, not a
+// literal source file under assets/, so re-running it through fingerprint
+// would fail looking for a source file that doesn't exist.
+func (p *assetPipeline) fingerprintHTML(html []byte) ([]byte, error) {
+	var rewriteErr error
+	out := assetRefRe.ReplaceAllFunc(html, func(match []byte) []byte {
+		sub := assetRefRe.FindSubmatch(match)
+		rel := strings.TrimPrefix(string(sub[2]), "/assets/")
+		if p.isFingerprinted(rel) {
+			return match
+		}
+		url, err := p.fingerprint(rel)
+		if err != nil {
+			rewriteErr = err
+			return match
+		}
+		return []byte(fmt.Sprintf(`%s="%s"`, sub[1], url))
+	})
+	if rewriteErr != nil {
+		return nil, rewriteErr
+	}
+	return out, nil
+}
+
+// isFingerprinted reports whether rel is already a fingerprinted output
+// path (as opposed to a literal source path under assets/).
+func (p *assetPipeline) isFingerprinted(rel string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fingerprinted[rel]
+}
+
+// fingerprint copies assets/rel (read from in) into
+// outDir/assets/<rel without ext>.<hash><ext> (e.g. css/site.css ->
+// css/site.<hash>.css, once per rel) and returns its public /assets/... URL.
+func (p *assetPipeline) fingerprint(rel string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if fingerprinted, ok := p.manifest[rel]; ok {
+		return "/assets/" + fingerprinted, nil
+	}
+
+	raw, err := fs.ReadFile(p.in, path.Join("assets", rel))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(raw)
+	hash := hex.EncodeToString(sum[:])[:8]
+	ext := path.Ext(rel)
+	fingerprinted := fmt.Sprintf("%s.%s%s", strings.TrimSuffix(rel, ext), hash, ext)
+
+	destPath := filepath.Join(p.outDir, "assets", fingerprinted)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(destPath, raw, 0o644); err != nil {
+		return "", err
+	}
+
+	p.manifest[rel] = fingerprinted
+	p.fingerprinted[fingerprinted] = true
+	return "/assets/" + fingerprinted, nil
+}
+
+// writeManifest dumps the original->fingerprinted mapping to outDir/manifest.json.
+func (p *assetPipeline) writeManifest() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	raw, err := json.MarshalIndent(p.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(p.outDir, "manifest.json"), raw, 0o644)
+}
+
+// funcMap exposes asset/fingerprint so templates can opt into fingerprinted
+// URLs directly instead of relying on the href/src scan.
+func (p *assetPipeline) funcMap() template.FuncMap {
+	return template.FuncMap{
+		"asset": func(rel string) string {
+			url, err := p.fingerprint(rel)
+			if err != nil {
+				return "/assets/" + rel
+			}
+			return url
+		},
+		"fingerprint": func(rel string) string {
+			url, err := p.fingerprint(rel)
+			if err != nil {
+				return rel
+			}
+			return strings.TrimPrefix(url, "/assets/")
+		},
+	}
+}
+
+// postProcess runs the optional asset-fingerprinting and minify stages over
+// a rendered page, in that order, before it's written to outDir.
+// Fingerprinting must run before minify: assetRefRe only matches quoted
+// href/src attributes, and tdewolff/minify strips quotes from attribute
+// values that don't need them (href="/assets/x" -> href=/assets/x), which
+// would otherwise make every /assets/... reference invisible to the scan.
+func postProcess(html []byte, assets *assetPipeline, minifyOn bool) ([]byte, error) {
+	out := html
+
+	if assets != nil {
+		fingerprinted, err := assets.fingerprintHTML(out)
+		if err != nil {
+			return nil, err
+		}
+		out = fingerprinted
+	}
+
+	if minifyOn {
+		minified, err := minifyHTML(out)
+		if err != nil {
+			return nil, err
+		}
+		out = minified
+	}
+
+	return out, nil
+}
+
+func minifyHTML(src []byte) ([]byte, error) {
+	m := minify.New()
+	m.AddFunc("text/html", minifyhtml.Minify)
+
+	var buf bytes.Buffer
+	if err := m.Minify("text/html", &buf, bytes.NewReader(src)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,195 @@
+package staticgen
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Page is one entry in a Collection: enough metadata about a Markdown page
+// to list it, sort it, and link to its neighbors.
+type Page struct {
+	Path  string // site-relative output path, e.g. /blog/foo.html
+	Title string
+	Date  time.Time
+	Tags  []string
+
+	Prev *Page
+	Next *Page
+}
+
+// Collection is the result of the opt-in pre-render pass: every Markdown
+// page sorted by date, plus a tag -> pages index used to auto-render tag
+// pages. Exposed to templates as .Site.Posts and .Site.Tags.
+type Collection struct {
+	Posts []*Page
+	Tags  map[string][]*Page
+
+	// byFile maps a Markdown source's fs.FS-relative path to its Page, so
+	// renderMarkdown can thread Prev/Next into that page's BaseData.
+	byFile map[string]*Page
+}
+
+// pageDateLayouts are the front-matter `date:` formats staticgen understands,
+// tried in order.
+var pageDateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// buildCollection scans every Markdown page's front matter and assembles a
+// Collection: posts sorted by date (newest first) with Prev/Next threaded
+// between neighbors, and a tag index built from each page's `tags:` list.
+func buildCollection(in fs.FS, mdFiles []string) (*Collection, error) {
+	posts := make([]*Page, 0, len(mdFiles))
+	byFile := make(map[string]*Page, len(mdFiles))
+
+	for _, mdFile := range mdFiles {
+		src, err := fs.ReadFile(in, mdFile)
+		if err != nil {
+			return nil, err
+		}
+		meta, _, err := splitFrontMatter(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: parsing front matter: %w", mdFile, err)
+		}
+
+		rel, ok := relUnderPages(mdFile)
+		if !ok {
+			rel = mdFile
+		}
+
+		page := &Page{
+			Path: "/" + trimMarkdownExt(rel) + ".html",
+			Date: pageDate(meta),
+		}
+		if title, ok := meta["title"].(string); ok {
+			page.Title = title
+		}
+		page.Tags = pageTags(meta)
+
+		posts = append(posts, page)
+		byFile[mdFile] = page
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].Date.After(posts[j].Date) })
+	for i, page := range posts {
+		if i > 0 {
+			page.Prev = posts[i-1]
+		}
+		if i < len(posts)-1 {
+			page.Next = posts[i+1]
+		}
+	}
+
+	tags := map[string][]*Page{}
+	for _, page := range posts {
+		for _, tag := range page.Tags {
+			tags[tag] = append(tags[tag], page)
+		}
+	}
+
+	return &Collection{Posts: posts, Tags: tags, byFile: byFile}, nil
+}
+
+// relUnderPages strips the leading "pages/" from an fs.FS-relative path.
+func relUnderPages(p string) (string, bool) {
+	rel := strings.TrimPrefix(p, "pages/")
+	return rel, rel != p
+}
+
+func trimMarkdownExt(rel string) string {
+	ext := path.Ext(rel)
+	return rel[:len(rel)-len(ext)]
+}
+
+func pageDate(meta map[string]any) time.Time {
+	raw, ok := meta["date"].(string)
+	if !ok {
+		return time.Time{}
+	}
+	for _, layout := range pageDateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func pageTags(meta map[string]any) []string {
+	raw, ok := meta["tags"].([]any)
+	if !ok {
+		return nil
+	}
+	tags := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			tags = append(tags, s)
+		}
+	}
+	return tags
+}
+
+// renderTagIndex renders _layouts/tag.html for one tag into
+// site/tags/<tagSlug(tag)>/index.html, exposing the tag and its pages as
+// .Page.Tag and .Page.Posts.
+func renderTagIndex(in fs.FS, outDir string, prototype *template.Template, global *siteGlobals, assets *assetPipeline, doMinify bool, tag string, pages []*Page, buildTimestamp string) error {
+	t, err := prototype.Clone()
+	if err != nil {
+		return err
+	}
+	if t, err = t.ParseFS(in, "_layouts/tag.html"); err != nil {
+		return err
+	}
+
+	data := BaseData{
+		Year:           time.Now().Year(),
+		BuildTimestamp: buildTimestamp,
+		Site:           global.Site,
+		Data:           global.Data,
+		Page: map[string]any{
+			"Tag":   tag,
+			"Posts": pages,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "tag", data); err != nil {
+		return fmt.Errorf("tag %q: executing layout: %w", tag, err)
+	}
+
+	out, err := postProcess(buf.Bytes(), assets, doMinify)
+	if err != nil {
+		return fmt.Errorf("tag %q: post-processing: %w", tag, err)
+	}
+
+	outPath := filepath.Join(outDir, "tags", tagSlug(tag), "index.html")
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out, 0o644)
+}
+
+// tagSlugRe matches runs of characters not safe to use in a single path
+// segment; everything else (slashes, whitespace, punctuation) collapses to
+// a single "-".
+var tagSlugRe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// tagSlug turns a front-matter `tags:` entry into a single safe path
+// segment. Front matter is arbitrary page content, so a tag like
+// "../../../tmp/x" must not be able to walk renderTagIndex's output path
+// outside outDir/tags.
+func tagSlug(tag string) string {
+	slug := tagSlugRe.ReplaceAllString(tag, "-")
+	slug = strings.ReplaceAll(slug, "..", "-")
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		slug = "tag"
+	}
+	return slug
+}
@@ -0,0 +1,120 @@
+package staticgen
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// dataFileExts are the data file formats recognized under _data/.
+var dataFileExts = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+	".toml": true,
+}
+
+// loadDataDir walks _data/**/*.{yaml,yml,json,toml} in in and builds a
+// nested map keyed by path, so _data/nav/main.yaml ends up reachable as
+// Data["nav"]["main"] (templates see it as .Data.nav.main). A missing
+// _data directory is not an error; it just yields an empty map.
+func loadDataDir(in fs.FS) (map[string]any, error) {
+	data := map[string]any{}
+
+	err := fs.WalkDir(in, "_data", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		ext := strings.ToLower(path.Ext(p))
+		if !dataFileExts[ext] {
+			return nil
+		}
+
+		rel := strings.TrimPrefix(strings.TrimSuffix(p, ext), "_data/")
+		segments := strings.Split(rel, "/")
+
+		value, err := decodeDataFile(in, p, ext)
+		if err != nil {
+			return err
+		}
+		setNested(data, segments, value)
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return data, nil
+}
+
+// loadSiteConfig reads _config.yaml, if present, into a map exposed to
+// templates as .Site (Title, BaseURL, Author, etc). A missing file yields an
+// empty map rather than an error.
+func loadSiteConfig(in fs.FS) (map[string]any, error) {
+	raw, err := fs.ReadFile(in, "_config.yaml")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return map[string]any{}, nil
+		}
+		return nil, err
+	}
+
+	site := map[string]any{}
+	if err := yaml.Unmarshal(raw, &site); err != nil {
+		return nil, err
+	}
+	return site, nil
+}
+
+func decodeDataFile(in fs.FS, p, ext string) (any, error) {
+	raw, err := fs.ReadFile(in, p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ext {
+	case ".yaml", ".yml":
+		var v any
+		err = yaml.Unmarshal(raw, &v)
+		return v, err
+	case ".json":
+		var v any
+		err = json.Unmarshal(raw, &v)
+		return v, err
+	case ".toml":
+		var v map[string]any
+		_, err = toml.Decode(string(raw), &v)
+		return v, err
+	default:
+		return nil, nil
+	}
+}
+
+// setNested assigns value at the nested location described by segments,
+// creating intermediate map[string]any levels as needed.
+func setNested(root map[string]any, segments []string, value any) {
+	cur := root
+	for i, seg := range segments {
+		if i == len(segments)-1 {
+			cur[seg] = value
+			return
+		}
+		next, ok := cur[seg].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			cur[seg] = next
+		}
+		cur = next
+	}
+}
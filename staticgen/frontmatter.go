@@ -0,0 +1,40 @@
+package staticgen
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterDelim is the fence staticgen looks for at the very top of a
+// Markdown source file.
+const frontMatterDelim = "---"
+
+// splitFrontMatter separates an optional leading `---`-fenced YAML block from
+// the remaining Markdown body. If the file does not start with the fence,
+// meta is nil and body is the whole input unchanged.
+func splitFrontMatter(src []byte) (meta map[string]any, body []byte, err error) {
+	lines := bytes.Split(src, []byte("\n"))
+	if len(lines) == 0 || string(bytes.TrimSpace(lines[0])) != frontMatterDelim {
+		return nil, src, nil
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if string(bytes.TrimSpace(lines[i])) != frontMatterDelim {
+			continue
+		}
+		rawMeta := bytes.Join(lines[1:i], []byte("\n"))
+		rest := bytes.Join(lines[i+1:], []byte("\n"))
+
+		meta = map[string]any{}
+		if len(bytes.TrimSpace(rawMeta)) > 0 {
+			if err := yaml.Unmarshal(rawMeta, &meta); err != nil {
+				return nil, nil, err
+			}
+		}
+		return meta, rest, nil
+	}
+
+	// Opening fence with no closing fence: treat the whole file as a body.
+	return nil, src, nil
+}
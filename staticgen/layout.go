@@ -0,0 +1,81 @@
+package staticgen
+
+import (
+	"html/template"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// resolveLayout picks the most specific set of layout files for a page,
+// mirroring Hugo's baseof cascade: a page under pages/blog/... first checks
+// for a section-specific base (_layouts/blog/baseof.html), then the site
+// default (_layouts/_default/baseof.html), and only then falls back to the
+// flat _layouts/*.html set staticgen has always supported. A candidate is
+// only picked if it actually defines layoutName; a section baseof that
+// overrides some other layout falls through to the next, less specific
+// candidate rather than failing at render time. prototype is the shared
+// include/funcs template (see RenderSite) and is cloned to probe each
+// candidate, so a baseof that calls a helper like nowRFC3339 or asset()
+// parses the same way it will at render time instead of failing the probe
+// on an undefined function. pageRel is the page's path relative to the
+// pages/ directory (e.g. "blog/post.template.html"). entry is always
+// layoutName; it's returned alongside files so callers can execute the
+// right template without re-deriving the section. Paths are fs.FS-relative
+// (forward-slash, rooted at srcDir) so callers can ParseFS them straight
+// out of an embedded tree.
+func resolveLayout(in fs.FS, prototype *template.Template, pageRel, layoutName string) (files []string, entry string, err error) {
+	if section, ok := firstPathSegment(pageRel); ok {
+		candidate := path.Join("_layouts", section, "baseof.html")
+		if definesLayout(in, prototype, candidate, layoutName) {
+			return []string{candidate}, layoutName, nil
+		}
+	}
+
+	defaultBase := path.Join("_layouts", "_default", "baseof.html")
+	if definesLayout(in, prototype, defaultBase, layoutName) {
+		return []string{defaultBase}, layoutName, nil
+	}
+
+	flat, err := fs.Glob(in, "_layouts/*.html")
+	if err != nil {
+		return nil, "", err
+	}
+	return flat, layoutName, nil
+}
+
+// definesLayout reports whether p exists and defines a template named
+// layoutName, so resolveLayout can skip a baseof that doesn't actually
+// provide the requested entry point instead of selecting it blindly. It
+// probes p by cloning prototype rather than starting from a bare
+// template.New, so a baseof using the same FuncMap the real render path
+// provides (nowRFC3339, asset, fingerprint) parses instead of failing the
+// probe with an undefined-function error.
+func definesLayout(in fs.FS, prototype *template.Template, p, layoutName string) bool {
+	if !fsFileExists(in, p) {
+		return false
+	}
+	t, err := prototype.Clone()
+	if err != nil {
+		return false
+	}
+	if t, err = t.ParseFS(in, p); err != nil {
+		return false
+	}
+	return t.Lookup(layoutName) != nil
+}
+
+// firstPathSegment returns the first slash-separated segment of rel (its
+// section), or ok=false if rel has no subdirectory (a top-level page).
+func firstPathSegment(rel string) (section string, ok bool) {
+	parts := strings.SplitN(rel, "/", 2)
+	if len(parts) < 2 {
+		return "", false
+	}
+	return parts[0], true
+}
+
+func fsFileExists(in fs.FS, p string) bool {
+	info, err := fs.Stat(in, p)
+	return err == nil && !info.IsDir()
+}
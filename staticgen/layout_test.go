@@ -0,0 +1,106 @@
+package staticgen
+
+import (
+	"html/template"
+	"testing"
+	"testing/fstest"
+)
+
+// testPrototype returns a bare prototype carrying templateFuncs (but not the
+// asset/fingerprint funcs RenderSite adds from its asset pipeline), so
+// resolveLayout's probe sees the same generator helpers as a real render.
+func testPrototype() *template.Template {
+	return template.New("root").Funcs(templateFuncs)
+}
+
+func TestResolveLayoutPrecedence(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_layouts/_default/baseof.html": {Data: []byte(`{{define "public"}}default{{end}}`)},
+		"_layouts/blog/baseof.html":     {Data: []byte(`{{define "public"}}blog{{end}}`)},
+		"_layouts/flat.html":            {Data: []byte(`{{define "public"}}flat{{end}}`)},
+	}
+
+	files, entry, err := resolveLayout(fsys, testPrototype(), "blog/post.template.html", "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != "public" {
+		t.Errorf("entry = %q, want %q", entry, "public")
+	}
+	want := []string{"_layouts/blog/baseof.html"}
+	if len(files) != 1 || files[0] != want[0] {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+}
+
+func TestResolveLayoutFallsBackToDefault(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_layouts/_default/baseof.html": {Data: []byte(`{{define "public"}}default{{end}}`)},
+		"_layouts/flat.html":            {Data: []byte(`{{define "public"}}flat{{end}}`)},
+	}
+
+	files, _, err := resolveLayout(fsys, testPrototype(), "blog/post.template.html", "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "_layouts/_default/baseof.html"
+	if len(files) != 1 || files[0] != want {
+		t.Errorf("files = %v, want [%s]", files, want)
+	}
+}
+
+// TestResolveLayoutSkipsSectionBaseThatLacksEntry covers a section baseof
+// that overrides some other layout but not the one being resolved: it
+// should not be picked just because it exists, and resolution should fall
+// through to the next, less specific candidate.
+func TestResolveLayoutSkipsSectionBaseThatLacksEntry(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_layouts/_default/baseof.html": {Data: []byte(`{{define "public"}}default{{end}}`)},
+		"_layouts/blog/baseof.html":     {Data: []byte(`{{define "dashboard"}}blog dashboard only{{end}}`)},
+	}
+
+	files, _, err := resolveLayout(fsys, testPrototype(), "blog/post.template.html", "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "_layouts/_default/baseof.html"
+	if len(files) != 1 || files[0] != want {
+		t.Errorf("files = %v, want [%s]", files, want)
+	}
+}
+
+func TestResolveLayoutFlatFallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_layouts/flat.html": {Data: []byte(`{{define "public"}}flat{{end}}`)},
+	}
+
+	files, _, err := resolveLayout(fsys, testPrototype(), "post.template.html", "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "_layouts/flat.html"
+	if len(files) != 1 || files[0] != want {
+		t.Errorf("files = %v, want [%s]", files, want)
+	}
+}
+
+// TestResolveLayoutSectionBaseUsesCustomFunc covers a section baseof that
+// calls a generator-provided template func (nowRFC3339): definesLayout's
+// probe must carry the same FuncMap the real render path uses, or a
+// well-formed baseof using a real func gets silently skipped as if it
+// didn't define the layout at all.
+func TestResolveLayoutSectionBaseUsesCustomFunc(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_layouts/_default/baseof.html": {Data: []byte(`{{define "public"}}default{{end}}`)},
+		"_layouts/blog/baseof.html":     {Data: []byte(`{{define "public"}}blog built {{nowRFC3339}}{{end}}`)},
+	}
+
+	files, _, err := resolveLayout(fsys, testPrototype(), "blog/post.template.html", "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "_layouts/blog/baseof.html"
+	if len(files) != 1 || files[0] != want {
+		t.Errorf("files = %v, want [%s]", files, want)
+	}
+}
@@ -0,0 +1,108 @@
+package staticgen
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+)
+
+// renderMarkdown renders a single Markdown page: it splits off the optional
+// YAML front matter, converts the body to HTML, and executes it through the
+// layout named by the front matter's `layout:` key (falling back to
+// "public"). Output paths mirror renderOne: pages/blog/foo.md -> site/blog/foo.html.
+// mdFile is fs.FS-relative (e.g. "pages/blog/foo.md"); outDir stays a real
+// OS directory since generated output is always written to disk.
+func renderMarkdown(in fs.FS, outDir string, prototype *template.Template, global *siteGlobals, assets *assetPipeline, doMinify bool, collection *Collection, mdFile string, buildTimestamp string) error {
+	src, err := fs.ReadFile(in, mdFile)
+	if err != nil {
+		return err
+	}
+
+	meta, body, err := splitFrontMatter(src)
+	if err != nil {
+		return fmt.Errorf("%s: parsing front matter: %w", mdFile, err)
+	}
+	if meta == nil {
+		meta = map[string]any{}
+	}
+
+	var htmlBody bytes.Buffer
+	if err := goldmark.Convert(body, &htmlBody); err != nil {
+		return fmt.Errorf("%s: rendering markdown: %w", mdFile, err)
+	}
+
+	layout := "public"
+	if name, ok := meta["layout"].(string); ok && name != "" {
+		layout = name
+	}
+
+	// Clone the shared include/funcs prototype so concurrent workers each get
+	// their own template set; Markdown bodies aren't themselves templates, so
+	// there's nothing page-specific to parse in yet.
+	t, err := prototype.Clone()
+	if err != nil {
+		return err
+	}
+
+	rel, ok := relUnderPages(mdFile)
+	if !ok {
+		return fmt.Errorf("%s: not under pages/", mdFile)
+	}
+	outName := strings.TrimSuffix(rel, ".md") + ".html"
+	outPath := filepath.Join(outDir, outName)
+
+	layoutFiles, layout, err := resolveLayout(in, prototype, rel, layout)
+	if err != nil {
+		return err
+	}
+	if len(layoutFiles) > 0 {
+		if t, err = t.ParseFS(in, layoutFiles...); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Rendering %s -> %s (layout: %s)", mdFile, outPath, layout)
+
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return err
+	}
+
+	data := BaseData{
+		Year:           time.Now().Year(),
+		BuildTimestamp: buildTimestamp,
+		Page:           meta,
+		Site:           global.Site,
+		Data:           global.Data,
+		Content:        template.HTML(htmlBody.String()),
+	}
+	if collection != nil {
+		if page := collection.byFile[mdFile]; page != nil {
+			data.Prev, data.Next = page.Prev, page.Next
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, layout, data); err != nil {
+		return fmt.Errorf("%s: executing layout %q: %w", mdFile, layout, err)
+	}
+
+	out, err := postProcess(buf.Bytes(), assets, doMinify)
+	if err != nil {
+		return fmt.Errorf("%s: post-processing: %w", mdFile, err)
+	}
+
+	if err := os.WriteFile(outPath, out, 0o644); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully wrote %s", outPath)
+	return nil
+}
@@ -0,0 +1,279 @@
+// Package staticgen is the library surface behind the staticgen CLI: it
+// builds a source tree (anything satisfying fs.FS) into a directory of
+// rendered HTML. The CLI is just one caller that points RenderSite at
+// os.DirFS(srcDir); a downstream program can instead //go:embed its own
+// source tree and call RenderSite directly to regenerate the site at
+// runtime with no source tree on disk. See examples/embedded.
+package staticgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type BaseData struct {
+	Year           int
+	BuildTimestamp string
+	// Page holds the current page's front matter (Markdown sources only);
+	// auto-rendered tag index pages get Tag and Posts here instead.
+	Page map[string]any
+	// Site holds _config.yaml (Title, BaseURL, Author, ...).
+	Site map[string]any
+	// Data holds the _data/ tree, keyed by path (Data["nav"]["main"]).
+	Data map[string]any
+	// Content is the rendered HTML body for Markdown pages.
+	Content template.HTML
+	// Prev/Next are this page's neighbors in .Site.Posts (collections only).
+	Prev *Page
+	Next *Page
+	// Add anything you want available to every page:
+	// UserName string
+	// Env      string
+}
+
+// templateFuncs is shared between html/template pages and Markdown pages so
+// layouts see the same helpers regardless of the page's source format.
+var templateFuncs = template.FuncMap{
+	"nowRFC3339": func() string { return time.Now().Format(time.RFC3339) },
+}
+
+// Options controls a RenderSite run. It's the library surface: the CLI
+// fills it in from flags and points it at os.DirFS(srcDir); a downstream
+// program can instead //go:embed its own source tree and call RenderSite
+// directly. See examples/embedded.
+type Options struct {
+	PagesGlob      string // glob for html/template pages, e.g. "pages/**/*.template.html"
+	MDGlob         string // glob for Markdown pages, e.g. "pages/**/*.md"
+	BuildTimestamp string
+	Jobs           int  // concurrent page renders; <1 means serial
+	Minify         bool // run rendered HTML through the minifier
+	Collections    bool // build Prev/Next and tag indexes from Markdown pages
+}
+
+// RenderSite is staticgen's library entry point: it builds everything under
+// in (an fs.FS rooted at the source tree) into outDir. The CLI backs in with
+// os.DirFS(srcDir); a downstream program can instead //go:embed its source
+// tree and call RenderSite directly to regenerate the site at runtime with
+// no source tree on disk. Output always lands on the real filesystem.
+func RenderSite(in fs.FS, outDir string, opts Options) error {
+	if opts.Jobs < 1 {
+		opts.Jobs = 1
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+
+	pageFiles, err := fs.Glob(in, opts.PagesGlob)
+	if err != nil {
+		return err
+	}
+	mdFiles, err := fs.Glob(in, opts.MDGlob)
+	if err != nil {
+		return err
+	}
+	if len(pageFiles) == 0 && len(mdFiles) == 0 {
+		return errors.New("no page templates found: " + opts.PagesGlob)
+	}
+	log.Printf("Found %d page template(s) and %d markdown page(s) to render", len(pageFiles), len(mdFiles))
+
+	// Common includes; layouts are resolved per-page via resolveLayout so a
+	// section can override just its baseof without duplicating templates.
+	// Parsed once into a prototype and Clone()'d per page so _includes isn't
+	// re-globbed/re-parsed for every page.
+	includeFiles, err := fs.Glob(in, "_includes/*.html")
+	if err != nil {
+		return err
+	}
+	assets := newAssetPipeline(in, outDir)
+
+	prototype := template.New("root").Funcs(templateFuncs).Funcs(assets.funcMap())
+	if len(includeFiles) > 0 {
+		if prototype, err = prototype.ParseFS(in, includeFiles...); err != nil {
+			return err
+		}
+	}
+
+	siteConfig, err := loadSiteConfig(in)
+	if err != nil {
+		return err
+	}
+	siteData, err := loadDataDir(in)
+	if err != nil {
+		return err
+	}
+	global := &siteGlobals{Site: siteConfig, Data: siteData}
+
+	var collection *Collection
+	if opts.Collections || truthy(siteConfig["collections"]) {
+		if collection, err = buildCollection(in, mdFiles); err != nil {
+			return err
+		}
+		global.Site["Posts"] = collection.Posts
+		global.Site["Tags"] = collection.Tags
+
+		for tag, pages := range collection.Tags {
+			if err := renderTagIndex(in, outDir, prototype, global, assets, opts.Minify, tag, pages, opts.BuildTimestamp); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := renderAll(in, outDir, prototype, global, assets, opts.Minify, collection, pageFiles, mdFiles, opts.BuildTimestamp, opts.Jobs); err != nil {
+		return err
+	}
+	return assets.writeManifest()
+}
+
+// truthy reports whether a _config.yaml value should be treated as "on"
+// (used for the `collections:` opt-in key).
+func truthy(v any) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+// siteGlobals bundles the site-wide data every page sees: _config.yaml as
+// Site and the _data/ tree as Data.
+type siteGlobals struct {
+	Site map[string]any
+	Data map[string]any
+}
+
+// renderAll fans pages out across a bounded worker pool, cloning the shared
+// include/funcs prototype per page so concurrent workers never share a
+// *template.Template. Errors from individual pages are aggregated rather
+// than aborting the run, so one broken template doesn't hide the rest.
+func renderAll(in fs.FS, outDir string, prototype *template.Template, global *siteGlobals, assets *assetPipeline, doMinify bool, collection *Collection, pageFiles, mdFiles []string, buildTimestamp string, jobs int) error {
+	type job struct {
+		path     string
+		markdown bool
+	}
+
+	jobsCh := make(chan job)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+
+	worker := func() {
+		defer wg.Done()
+		for j := range jobsCh {
+			var err error
+			if j.markdown {
+				err = renderMarkdown(in, outDir, prototype, global, assets, doMinify, collection, j.path, buildTimestamp)
+			} else {
+				err = renderOne(in, outDir, prototype, global, assets, doMinify, j.path, buildTimestamp)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}
+	}
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go worker()
+	}
+
+	for _, p := range pageFiles {
+		jobsCh <- job{path: p}
+	}
+	for _, p := range mdFiles {
+		jobsCh <- job{path: p, markdown: true}
+	}
+	close(jobsCh)
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// renderOne renders one html/template page. pageFile is fs.FS-relative (e.g.
+// "pages/blog/post.template.html"); outDir stays a real OS directory since
+// generated output is always written to disk.
+func renderOne(in fs.FS, outDir string, prototype *template.Template, global *siteGlobals, assets *assetPipeline, doMinify bool, pageFile string, buildTimestamp string) error {
+	// Clone the shared include/funcs prototype so concurrent workers each get
+	// their own template set, then parse in this page's own template
+	t, err := prototype.Clone()
+	if err != nil {
+		return err
+	}
+	if t, err = t.ParseFS(in, pageFile); err != nil {
+		return err
+	}
+
+	// Default layout
+	layout := "public"
+
+	// Detect optional {{define "layout_name"}}dashboard{{end}} etc.
+	if t.Lookup("layout_name") != nil {
+		var b strings.Builder
+		if err := t.ExecuteTemplate(&b, "layout_name", nil); err == nil {
+			name := strings.TrimSpace(b.String())
+			if name != "" {
+				layout = name
+			}
+		}
+	}
+
+	// Determine output path: pages/foo.template.html -> site/foo.html
+	rel, ok := relUnderPages(pageFile)
+	if !ok {
+		return fmt.Errorf("%s: not under pages/", pageFile)
+	}
+	outName := strings.TrimSuffix(rel, ".template.html") + ".html"
+	outPath := filepath.Join(outDir, outName)
+
+	// Resolve and parse in the most specific layout for this page's section.
+	layoutFiles, layout, err := resolveLayout(in, prototype, rel, layout)
+	if err != nil {
+		return err
+	}
+	if len(layoutFiles) > 0 {
+		if t, err = t.ParseFS(in, layoutFiles...); err != nil {
+			return err
+		}
+	}
+
+	// Log the rendering operation
+	log.Printf("Rendering %s -> %s (layout: %s)", pageFile, outPath, layout)
+
+	// Ensure subdirs exist
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return err
+	}
+
+	data := BaseData{
+		Year:           time.Now().Year(),
+		BuildTimestamp: buildTimestamp,
+		Site:           global.Site,
+		Data:           global.Data,
+	}
+	// Execute the top-level layout so it pulls in header/footer and the page blocks
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, layout, data); err != nil {
+		return fmt.Errorf("%s: executing layout %q: %w", pageFile, layout, err)
+	}
+
+	out, err := postProcess(buf.Bytes(), assets, doMinify)
+	if err != nil {
+		return fmt.Errorf("%s: post-processing: %w", pageFile, err)
+	}
+
+	if err := os.WriteFile(outPath, out, 0o644); err != nil {
+		return err
+	}
+
+	// Log successful completion
+	log.Printf("Successfully wrote %s", outPath)
+	return nil
+}